@@ -0,0 +1,289 @@
+package main
+
+import (
+	"math/bits"
+	"math/rand"
+)
+
+// Bitboard is a 64-bit set of squares, one bit per square 0..63, laid out
+// the same way as Board/Square: bit 0 is a8, bit 7 is h8, bit 56 is a1, bit
+// 63 is h1.
+type Bitboard uint64
+
+func bit(s Square) Bitboard { return Bitboard(1) << uint(s) }
+
+// popLSB returns the lowest set square in *bb and clears it.
+func popLSB(bb *Bitboard) Square {
+	s := Square(bits.TrailingZeros64(uint64(*bb)))
+	*bb &= *bb - 1
+	return s
+}
+
+// pieceOrder fixes which bitboard slot each piece lives in: 0-5 are our
+// pieces (P N B R Q K), 6-11 are theirs (p n b r q k), matching the
+// side-to-move-relative convention the rest of the engine uses.
+var pieceOrder = [12]Piece{'P', 'N', 'B', 'R', 'Q', 'K', 'p', 'n', 'b', 'r', 'q', 'k'}
+
+const (
+	idxP = iota
+	idxN
+	idxB
+	idxR
+	idxQ
+	idxK
+	idxp
+	idxn
+	idxb
+	idxr
+	idxq
+	idxk
+)
+
+func pieceIdx(p Piece) int {
+	for i, q := range pieceOrder {
+		if q == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// Board is a bitboard representation of a chess position: twelve piece
+// bitboards, one per piece type and side, indexed via pieceIdx/pieceOrder.
+type Board struct {
+	bb [12]Bitboard
+}
+
+func (b Board) pieceAt(s Square) Piece {
+	mask := bit(s)
+	for i, p := range pieceOrder {
+		if b.bb[i]&mask != 0 {
+			return p
+		}
+	}
+	return '.'
+}
+
+func (b Board) occupied() (o Bitboard) {
+	for _, x := range b.bb {
+		o |= x
+	}
+	return o
+}
+
+func (b Board) oursOccupied() (o Bitboard) {
+	for i := 0; i < 6; i++ {
+		o |= b.bb[i]
+	}
+	return o
+}
+
+func (b Board) theirsOccupied() (o Bitboard) {
+	for i := 6; i < 12; i++ {
+		o |= b.bb[i]
+	}
+	return o
+}
+
+// Flip creates a flipped version of the board: squares are mirrored
+// top-to-bottom and left-to-right (equivalent to reversing bit order,
+// since Square.Flip is 63-s) and piece colors swap, matching Piece.Flip.
+func (b Board) Flip() (nb Board) {
+	for i := 0; i < 6; i++ {
+		nb.bb[i] = reverseBits(b.bb[i+6])
+		nb.bb[i+6] = reverseBits(b.bb[i])
+	}
+	return nb
+}
+
+func reverseBits(b Bitboard) Bitboard { return Bitboard(bits.Reverse64(uint64(b))) }
+
+// String returns a human-readable board representation as an 8x8 square
+// with pieces and dots.
+func (b Board) String() (s string) {
+	s = "\n"
+	for row := 0; row < 8; row++ {
+		for col := 0; col < 8; col++ {
+			s += string(b.pieceAt(Square(row*8 + col)))
+		}
+		s += "\n"
+	}
+	return s
+}
+
+// Leaper (knight/king) attack tables, precomputed at init.
+var knightAttacks [64]Bitboard
+var kingAttacks [64]Bitboard
+
+var knightDeltas = [8][2]int{{-2, -1}, {-2, 1}, {-1, -2}, {-1, 2}, {1, -2}, {1, 2}, {2, -1}, {2, 1}}
+var kingDeltas = [8][2]int{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
+
+func initLeaperAttacks() {
+	for sq := 0; sq < 64; sq++ {
+		r0, c0 := sq/8, sq%8
+		for _, d := range knightDeltas {
+			r, c := r0+d[0], c0+d[1]
+			if r >= 0 && r < 8 && c >= 0 && c < 8 {
+				knightAttacks[sq] |= bit(Square(r*8 + c))
+			}
+		}
+		for _, d := range kingDeltas {
+			r, c := r0+d[0], c0+d[1]
+			if r >= 0 && r < 8 && c >= 0 && c < 8 {
+				kingAttacks[sq] |= bit(Square(r*8 + c))
+			}
+		}
+	}
+}
+
+var rookDeltas = [4][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+var bishopDeltas = [4][2]int{{-1, -1}, {-1, 1}, {1, -1}, {1, 1}}
+
+// slidingAttacksSlow computes the attack set for a slider on sq given a
+// fixed set of occupied squares, by walking each ray until it hits the
+// edge of the board or a blocker (blockers themselves are included, since
+// they may be capturable). Used only to build the magic bitboard tables.
+func slidingAttacksSlow(sq Square, occ Bitboard, deltas [4][2]int) Bitboard {
+	var attacks Bitboard
+	r0, c0 := int(sq)/8, int(sq)%8
+	for _, d := range deltas {
+		r, c := r0+d[0], c0+d[1]
+		for r >= 0 && r < 8 && c >= 0 && c < 8 {
+			s := Square(r*8 + c)
+			attacks |= bit(s)
+			if occ&bit(s) != 0 {
+				break
+			}
+			r += d[0]
+			c += d[1]
+		}
+	}
+	return attacks
+}
+
+// relevantMask returns the blocker mask for sq: every square a slider's ray
+// could be blocked by, excluding the final square of each ray (squares on
+// the board edge never need their own occupancy bit, since a piece there is
+// always the end of the ray regardless of what's beyond it).
+func relevantMask(sq Square, deltas [4][2]int) Bitboard {
+	var mask Bitboard
+	r0, c0 := int(sq)/8, int(sq)%8
+	for _, d := range deltas {
+		r, c := r0+d[0], c0+d[1]
+		for inBounds(r, c) && inBounds(r+d[0], c+d[1]) {
+			mask |= bit(Square(r*8 + c))
+			r += d[0]
+			c += d[1]
+		}
+	}
+	return mask
+}
+
+func inBounds(r, c int) bool { return r >= 0 && r < 8 && c >= 0 && c < 8 }
+
+// indexToOccupancy maps an index in [0, 2^popcount(mask)) to one of the
+// occupancy subsets of mask (the "carry-rippler" enumeration).
+func indexToOccupancy(index int, mask Bitboard) Bitboard {
+	var occ Bitboard
+	m := mask
+	for i := 0; m != 0; i++ {
+		sq := popLSB(&m)
+		if index&(1<<uint(i)) != 0 {
+			occ |= bit(sq)
+		}
+	}
+	return occ
+}
+
+// magicEntry is the per-square data needed to look up slider attacks in
+// O(1): occ&mask, multiplied by magic and shifted, indexes table.
+type magicEntry struct {
+	mask  Bitboard
+	magic uint64
+	shift uint
+	table []Bitboard
+}
+
+var rookMagics [64]magicEntry
+var bishopMagics [64]magicEntry
+
+// findMagic searches for a 64-bit multiplier that maps every occupancy
+// subset of mask to a collision-free slot, then returns it along with the
+// populated attack table.
+func findMagic(sq Square, deltas [4][2]int, mask Bitboard) (uint64, []Bitboard) {
+	bitsInMask := bits.OnesCount64(uint64(mask))
+	n := 1 << bitsInMask
+	shift := uint(64 - bitsInMask)
+
+	occs := make([]Bitboard, n)
+	atks := make([]Bitboard, n)
+	for i := 0; i < n; i++ {
+		occs[i] = indexToOccupancy(i, mask)
+		atks[i] = slidingAttacksSlow(sq, occs[i], deltas)
+	}
+
+	rnd := rand.New(rand.NewSource(int64(sq)*2 + 1))
+	table := make([]Bitboard, n)
+	used := make([]bool, n)
+	for {
+		magic := rnd.Uint64() & rnd.Uint64() & rnd.Uint64()
+		if bits.OnesCount64(uint64(mask)*magic&0xFF00000000000000) < 6 {
+			continue
+		}
+		for i := range used {
+			used[i] = false
+		}
+		ok := true
+		for i := 0; i < n && ok; i++ {
+			idx := (uint64(occs[i]) * magic) >> shift
+			if !used[idx] {
+				used[idx] = true
+				table[idx] = atks[i]
+			} else if table[idx] != atks[i] {
+				ok = false
+			}
+		}
+		if ok {
+			result := make([]Bitboard, n)
+			copy(result, table)
+			return magic, result
+		}
+	}
+}
+
+func initMagics() {
+	for sq := 0; sq < 64; sq++ {
+		rmask := relevantMask(Square(sq), rookDeltas)
+		rmagic, rtable := findMagic(Square(sq), rookDeltas, rmask)
+		rookMagics[sq] = magicEntry{mask: rmask, magic: rmagic, shift: uint(64 - bits.OnesCount64(uint64(rmask))), table: rtable}
+
+		bmask := relevantMask(Square(sq), bishopDeltas)
+		bmagic, btable := findMagic(Square(sq), bishopDeltas, bmask)
+		bishopMagics[sq] = magicEntry{mask: bmask, magic: bmagic, shift: uint(64 - bits.OnesCount64(uint64(bmask))), table: btable}
+	}
+}
+
+func init() {
+	initLeaperAttacks()
+	initMagics()
+}
+
+// RookAttacks returns the rook attack bitboard from sq given occ, the set
+// of currently occupied squares.
+func RookAttacks(sq Square, occ Bitboard) Bitboard {
+	e := &rookMagics[sq]
+	idx := (uint64(occ&e.mask) * e.magic) >> e.shift
+	return e.table[idx]
+}
+
+// BishopAttacks returns the bishop attack bitboard from sq given occ.
+func BishopAttacks(sq Square, occ Bitboard) Bitboard {
+	e := &bishopMagics[sq]
+	idx := (uint64(occ&e.mask) * e.magic) >> e.shift
+	return e.table[idx]
+}
+
+// QueenAttacks returns the queen attack bitboard from sq given occ.
+func QueenAttacks(sq Square, occ Bitboard) Bitboard {
+	return RookAttacks(sq, occ) | BishopAttacks(sq, occ)
+}
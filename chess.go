@@ -2,6 +2,8 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -66,84 +68,205 @@ func (p Piece) Flip() Piece {
 	}
 }
 
-type Board [120]Piece
-
-// Flip creates a flipped version of the board
-func (a Board) Flip() (b Board) {
-	for i := len(a) - 1; i >= 0; i-- {
-		b[i] = a[len(a)-i-1].Flip()
-	}
-	return b
-}
-
-// String returns a human-readable board representation as a 8x8 square with
-// pieces and dots.
-func (a Board) String() (s string) {
-	s = "\n"
-	for row := 2; row < 10; row++ {
-		for col := 1; col < 9; col++ {
-			s = s + string(a[row*10+col])
-		}
-		s = s + "\n"
+// FEN parses a FEN record's board, side to move, castling rights, and
+// en-passant square. Castling rights and the en-passant square are
+// returned in absolute (White's point of view) terms; PositionFromFEN
+// translates them into Position's side-to-move-relative wc/bc/ep.
+func FEN(fen string) (b Board, whiteCastle, blackCastle [2]bool, ep Square, err error) {
+	parts := strings.Fields(fen)
+	if len(parts) == 0 {
+		return b, whiteCastle, blackCastle, ep, errors.New("empty FEN")
 	}
-	return s
-}
-
-// function for taking a fen representation of a board and returning a board
-func FEN(fen string) (b Board, err error) {
-	parts := strings.Split(fen, " ")
 	rows := strings.Split(parts[0], "/")
 	if len(rows) != 8 {
-		return b, errors.New("FEN should have 8 rows")
-	}
-	for i := 0; i < len(b); i++ {
-		b[i] = ' '
+		return b, whiteCastle, blackCastle, ep, errors.New("FEN should have 8 rows")
 	}
 	for i := 0; i < 8; i++ {
-		index := i*10 + 21
+		col := 0
 		for _, c := range rows[i] {
 			q := Piece(c)
 			if q >= '1' && q <= '8' {
-				for j := Piece(0); q-j >= '1'; j++ {
-					b[index] = '.'
-					index++
-				}
+				col += int(q - '0')
 			} else if q.value() == 0 && q.Flip().value() == 0 {
-				return b, errors.New("invalid piece value: " + string(c))
+				return b, whiteCastle, blackCastle, ep, errors.New("invalid piece value: " + string(c))
 			} else {
-				b[index] = q
-				index++
+				idx := pieceIdx(q)
+				if idx < 0 || col >= 8 {
+					return b, whiteCastle, blackCastle, ep, errors.New("invalid piece value: " + string(c))
+				}
+				b.bb[idx] |= bit(Square(i*8 + col))
+				col++
 			}
 		}
-		if index%10 != 9 {
-			return b, errors.New("invalid row length")
+		if col != 8 {
+			return b, whiteCastle, blackCastle, ep, errors.New("invalid row length")
+		}
+	}
+
+	if len(parts) > 2 && parts[2] != "-" {
+		for _, c := range parts[2] {
+			switch c {
+			case 'K':
+				whiteCastle[1] = true
+			case 'Q':
+				whiteCastle[0] = true
+			case 'k':
+				blackCastle[1] = true
+			case 'q':
+				blackCastle[0] = true
+			}
+		}
+	}
+
+	if len(parts) > 3 && parts[3] != "-" {
+		if sq, ok := parseSquare(parts[3]); ok {
+			ep = sq
 		}
 	}
+
 	if len(parts) > 1 && parts[1] == "b" {
 		b = b.Flip()
 	}
-	return b, nil
+	return b, whiteCastle, blackCastle, ep, nil
+}
+
+// PositionFromFEN parses fen into a ready-to-use Position, including
+// castling rights and the en-passant square translated into Position's
+// side-to-move-relative wc/bc/ep, plus the absolute color to move.
+func PositionFromFEN(fen string) (pos Position, color Color, err error) {
+	board, whiteCastle, blackCastle, ep, err := FEN(fen)
+	if err != nil {
+		return pos, color, err
+	}
+	color = White
+	if parts := strings.Fields(fen); len(parts) > 1 && parts[1] == "b" {
+		color = Black
+	}
+	// wc always indexes [A1, H1] and bc always indexes [H8, A8] (see
+	// Position.Move), which for the side made "ours" by a 180-degree board
+	// rotation swaps queenside and kingside: rotation reverses file order,
+	// so Black's real kingside (h-file) rook lands on relative A1 and its
+	// queenside (a-file) rook lands on relative H1. White is never
+	// rotated, so its rights carry over in [Q, K] order unchanged.
+	wc, bc := whiteCastle, [2]bool{blackCastle[1], blackCastle[0]}
+	if color == Black {
+		wc, bc = [2]bool{blackCastle[1], blackCastle[0]}, whiteCastle
+		ep = flipSquareOrZero(ep)
+	}
+	pos = Position{board: board, score: boardScore(board), wc: wc, bc: bc, ep: ep}
+	pos.hash = computeHash(pos)
+	return pos, color, nil
+}
+
+// flipIf converts s between the absolute (White's point of view) and
+// side-to-move-relative square numbering: Flip is its own inverse, so the
+// same helper works in either direction depending on which one s is in.
+func flipIf(s Square, color Color) Square {
+	if color == Black {
+		return s.Flip()
+	}
+	return s
+}
+
+// FEN renders pos as a full FEN record. Position alone has no notion of
+// absolute color or move counters (see the Color doc comment in uci.go), so
+// the caller supplies them; GameState, which tracks all three across a
+// game, is the usual source.
+func (pos Position) FEN(color Color, halfmove, fullmove int) string {
+	board := pos.board
+	if color == Black {
+		board = board.Flip()
+	}
+
+	rows := make([]string, 8)
+	for row := 0; row < 8; row++ {
+		var b strings.Builder
+		empty := 0
+		for col := 0; col < 8; col++ {
+			p := board.pieceAt(Square(row*8 + col))
+			if p == '.' {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				b.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			b.WriteByte(byte(p))
+		}
+		if empty > 0 {
+			b.WriteString(strconv.Itoa(empty))
+		}
+		rows[row] = b.String()
+	}
+
+	side := "w"
+	if color == Black {
+		side = "b"
+	}
+
+	// wc/bc are "ours"/"theirs" relative like the rest of Position, not
+	// literally white/black, so which one is White's depends on color.
+	// whiteRights always indexes [Q, K] (its side is never rotated), but
+	// blackRights indexes [k, q]: see the matching comment in
+	// PositionFromFEN for why rotation reverses Black's queenside/kingside
+	// order.
+	whiteRights, blackRights := pos.wc, pos.bc
+	if color == Black {
+		whiteRights, blackRights = pos.bc, pos.wc
+	}
+	castle := ""
+	if whiteRights[1] {
+		castle += "K"
+	}
+	if whiteRights[0] {
+		castle += "Q"
+	}
+	if blackRights[0] {
+		castle += "k"
+	}
+	if blackRights[1] {
+		castle += "q"
+	}
+	if castle == "" {
+		castle = "-"
+	}
+
+	ep := "-"
+	if pos.ep != 0 {
+		ep = flipIf(pos.ep, color).String()
+	}
+
+	return fmt.Sprintf("%s %s %s %s %d %d", strings.Join(rows, "/"), side, castle, ep, halfmove, fullmove)
 }
 
-// Square represents an index of the chess board.
+// Square represents an index of the chess board, 0 (a8) through 63 (h1).
 type Square int
 
-const A1, H1, A8, H8 Square = 91, 98, 21, 28
+const A1, H1, A8, H8 Square = 56, 63, 0, 7
 
-func (s Square) Flip() Square   { return 119 - s }
-func (s Square) String() string { return string([]byte{" abcdefgh "[s%10], "  87654321  "[s/10]}) }
+func (s Square) Flip() Square   { return 63 - s }
+func (s Square) String() string { return string([]byte{"abcdefgh"[s%8], "87654321"[s/8]}) }
 
-// Move direction constants, horizontal moves +/-1, vertical moves +/-10
-const N, E, S, W = -10, 1, 10, -1
+// Move direction constants, horizontal moves +/-1, vertical moves +/-8
+const N, E, S, W = -8, 1, 8, -1
 
 // Move represents a movement of a piece from one square to another.
 type Move struct {
-	from Square
-	to   Square
+	from  Square
+	to    Square
+	promo Piece // piece a pawn promotes to, or 0 for a non-promoting move (Position.Move defaults that to a queen promotion)
 }
 
-// Moves are printed in algebraic notation, i.e "e2e4".
-func (m Move) String() string { return m.from.String() + m.to.String() }
+// Moves are printed in algebraic notation, i.e "e2e4", with a lowercase
+// promotion suffix when set, i.e "e7e8q".
+func (m Move) String() string {
+	s := m.from.String() + m.to.String()
+	if m.promo != 0 {
+		s += string([]byte{byte(m.promo) + 'a' - 'A'})
+	}
+	return s
+}
 
 // Position describes a board with the current game state (en passant and castling rules).
 type Position struct {
@@ -153,6 +276,7 @@ type Position struct {
 	bc    [2]bool // black castling possibilities
 	ep    Square  // en-passant square where pawn can be captured
 	kp    Square  // king passent during castling, where kind can be captured
+	hash  uint64  // Zobrist hash of board+castling+ep, used as the transposition table key
 }
 
 // Rotate returns a modified position where the board is flipped, score is
@@ -163,75 +287,215 @@ func (pos Position) Flip() Position {
 		score: -pos.score,
 		wc:    [2]bool{pos.bc[0], pos.bc[1]},
 		bc:    [2]bool{pos.wc[0], pos.wc[1]},
-		ep:    pos.ep.Flip(),
-		kp:    pos.kp.Flip(),
+		ep:    flipSquareOrZero(pos.ep),
+		kp:    flipSquareOrZero(pos.kp),
+		// zobristPieceSq/zobristEp are built mirror-symmetric so a
+		// position's hash is unchanged by Board.Flip; no recomputation
+		// needed here.
+		hash: pos.hash,
 	}
 	np.board = pos.board.Flip()
 	return np
 }
 
+// flipSquareOrZero flips s unless it is the zero value, which ep and kp use
+// to mean "none"; Square(0) is itself a real board square (a8), so it must
+// be special-cased rather than flipped like a normal square.
+func flipSquareOrZero(s Square) Square {
+	if s == 0 {
+		return 0
+	}
+	return s.Flip()
+}
+
+// onBoard reports whether s is a valid square index.
+func onBoard(s Square) bool { return s >= 0 && s < 64 }
+
+// fileDist returns the absolute file (column) distance between two squares.
+func fileDist(a, b Square) int { return abs(int(a)%8 - int(b)%8) }
+
+// sign returns -1, 0, or 1 according to the sign of n.
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// rayBetween returns the squares strictly between a and b, exclusive of
+// both ends, if they share a rank, file, or diagonal; otherwise 0. Used to
+// find the path a king or rook must have clear to castle, the squares
+// that block a check from a slider, and the squares a pinned piece may
+// still move to without exposing its king (see legal.go).
+func rayBetween(a, b Square) (between Bitboard) {
+	ar, ac := int(a)/8, int(a)%8
+	br, bc := int(b)/8, int(b)%8
+	dr, dc := sign(br-ar), sign(bc-ac)
+	if dr == 0 && dc == 0 {
+		return 0
+	}
+	if dr != 0 && dc != 0 && abs(br-ar) != abs(bc-ac) {
+		return 0
+	}
+	for r, c := ar+dr, ac+dc; r != br || c != bc; r, c = r+dr, c+dc {
+		between |= bit(Square(r*8 + c))
+	}
+	return between
+}
+
 func (pos Position) Moves() (moves []Move) {
-	//all possible movement directions for each piece type
-	var directions = map[Piece][]Square{
-		'P': {N, N + N, N + W, N + E},
-		'N': {N + N + E, E + N + E, E + S + E, S + S + E, S + S + W, W + S + W, W + N + W, N + N + W},
-		'B': {N + E, S + E, S + W, N + W},
-		'R': {N, E, S, W},
-		'Q': {N, E, S, W, N + E, S + E, S + W, N + W},
-		'K': {N, E, S, W, N + E, S + E, S + W, N + W},
-	}
-	//iterate over all squares, considering squares with our pieces only
-	for index, p := range pos.board {
-		if !p.ours() {
-			continue
+	occ := pos.board.occupied()
+	notOurs := ^pos.board.oursOccupied()
+	theirs := pos.board.theirsOccupied()
+
+	// addPawnMove appends a pawn move from i to to, expanding it into one
+	// move per promotion piece when to lands on the last rank.
+	addPawnMove := func(i, to Square) {
+		if A8 <= to && to <= H8 {
+			for _, promo := range [4]Piece{'Q', 'R', 'B', 'N'} {
+				moves = append(moves, Move{from: i, to: to, promo: promo})
+			}
+			return
 		}
-		i := Square(index)
-		//iterate over all move directions for the given piece
-		for _, d := range directions[p] {
-			for j := i + d; ; j = j + d {
-				q := pos.board[j]
-				if q == ' ' || (q != '.' && q.ours()) {
-					break
-				}
-				//if piece is a pawn then check en passant rules/capture squares
-				if p == 'P' {
-					if (d == N || d == N+N) && q != '.' {
-						break
-					}
-					if d == N+N && (i < A1+N || pos.board[i+N] != '.') {
-						break
-					}
-					if (d == N+W || d == N+E) && q == '.' && (j != pos.ep && j != pos.kp && j != pos.kp-1 && j != pos.kp+1) {
-						break
-					}
-				}
-				moves = append(moves, Move{from: i, to: j})
-				//crawling pieces should stop after a single move
-				if p == 'P' || p == 'N' || p == 'K' || (q != ' ' && q != '.' && !q.ours()) {
-					break
-				}
-				//castling rules
-				if i == A1 && pos.board[j+E] == 'K' && pos.wc[0] {
-					moves = append(moves, Move{from: j + E, to: j + W})
-				}
-				if i == H1 && pos.board[j+W] == 'K' && pos.wc[1] {
-					moves = append(moves, Move{from: j + W, to: j + E})
+		moves = append(moves, Move{from: i, to: to})
+	}
+
+	pawns := pos.board.bb[idxP]
+	for pawns != 0 {
+		i := popLSB(&pawns)
+		if to := i + N; onBoard(to) && occ&bit(to) == 0 {
+			addPawnMove(i, to)
+			if to2 := i + 2*N; i/8 == 6 && occ&bit(to2) == 0 {
+				moves = append(moves, Move{from: i, to: to2})
+			}
+		}
+		for _, d := range []Square{N + W, N + E} {
+			to := i + d
+			if !onBoard(to) || fileDist(i, to) != 1 {
+				continue
+			}
+			if occ&bit(to) != 0 {
+				if theirs&bit(to) != 0 {
+					addPawnMove(i, to)
 				}
+			} else if to == pos.ep || to == pos.kp || to == pos.kp-1 || to == pos.kp+1 {
+				moves = append(moves, Move{from: i, to: to})
 			}
 		}
 	}
+
+	knights := pos.board.bb[idxN]
+	for knights != 0 {
+		i := popLSB(&knights)
+		targets := knightAttacks[i] & notOurs
+		for targets != 0 {
+			moves = append(moves, Move{from: i, to: popLSB(&targets)})
+		}
+	}
+
+	bishops := pos.board.bb[idxB]
+	for bishops != 0 {
+		i := popLSB(&bishops)
+		targets := BishopAttacks(i, occ) & notOurs
+		for targets != 0 {
+			moves = append(moves, Move{from: i, to: popLSB(&targets)})
+		}
+	}
+
+	rooks := pos.board.bb[idxR]
+	for rooks != 0 {
+		i := popLSB(&rooks)
+		targets := RookAttacks(i, occ) & notOurs
+		for targets != 0 {
+			moves = append(moves, Move{from: i, to: popLSB(&targets)})
+		}
+	}
+
+	queens := pos.board.bb[idxQ]
+	for queens != 0 {
+		i := popLSB(&queens)
+		targets := QueenAttacks(i, occ) & notOurs
+		for targets != 0 {
+			moves = append(moves, Move{from: i, to: popLSB(&targets)})
+		}
+	}
+
+	kings := pos.board.bb[idxK]
+	for kings != 0 {
+		i := popLSB(&kings)
+		targets := kingAttacks[i] & notOurs
+		for targets != 0 {
+			moves = append(moves, Move{from: i, to: popLSB(&targets)})
+		}
+		// castling rules: rook must still be on its home square and every
+		// square between it and the king must be empty. The king isn't
+		// necessarily 3/2 squares from A1/H1 here: Board.Flip is a full
+		// 180-degree rotation, which for the side made "ours" by an odd
+		// number of flips swaps queenside and kingside (see the comment on
+		// PositionFromFEN), leaving the king one file off its usual start.
+		if pos.wc[0] && pos.board.bb[idxR]&bit(A1) != 0 && occ&rayBetween(A1, i) == 0 {
+			moves = append(moves, Move{from: i, to: i + 2*W})
+		}
+		if pos.wc[1] && pos.board.bb[idxR]&bit(H1) != 0 && occ&rayBetween(H1, i) == 0 {
+			moves = append(moves, Move{from: i, to: i + 2*E})
+		}
+	}
 	return moves
 }
 
+// inCheck reports whether the side to move in pos has its king attacked. It
+// only tests for attacks on the king square, not whether a legal reply
+// exists, since Moves does not yet filter for legality.
+func inCheck(pos Position) bool {
+	b := pos.board
+	kingBB := b.bb[idxK]
+	if kingBB == 0 {
+		return false
+	}
+	king := popLSB(&kingBB)
+	occ := b.occupied()
+	if knightAttacks[king]&b.bb[idxn] != 0 {
+		return true
+	}
+	if kingAttacks[king]&b.bb[idxk] != 0 {
+		return true
+	}
+	if RookAttacks(king, occ)&(b.bb[idxr]|b.bb[idxq]) != 0 {
+		return true
+	}
+	if BishopAttacks(king, occ)&(b.bb[idxb]|b.bb[idxq]) != 0 {
+		return true
+	}
+	for _, from := range [2]Square{king - S - W, king - S - E} {
+		if onBoard(from) && fileDist(king, from) == 1 && b.bb[idxp]&bit(from) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // Move returns a modified rotated position after the move is applied.
 func (pos Position) Move(m Move) (np Position) {
-	i, j, p := m.from, m.to, pos.board[m.from]
+	i, j, p := m.from, m.to, pos.board.pieceAt(m.from)
 	np = pos
 	np.ep = 0
 	np.kp = 0
 	np.score = pos.score + pos.value(m)
-	np.board[m.to] = pos.board[m.from]
-	np.board[m.from] = '.'
+
+	pIdx := pieceIdx(p)
+	np.board.bb[pIdx] &^= bit(i)
+	np.hash ^= zobristPieceSq[pIdx][i]
+	if cap := pos.board.pieceAt(j); cap != '.' {
+		np.board.bb[pieceIdx(cap)] &^= bit(j)
+		np.hash ^= zobristPieceSq[pieceIdx(cap)][j]
+	}
+	np.board.bb[pIdx] |= bit(j)
+	np.hash ^= zobristPieceSq[pIdx][j]
+
 	if i == A1 {
 		np.wc[0] = false
 	}
@@ -247,46 +511,101 @@ func (pos Position) Move(m Move) (np Position) {
 	if p == 'K' {
 		np.wc[0], np.wc[1] = false, false
 		if abs(int(j-i)) == 2 {
+			rookFrom, rookTo := H1, (i+j)/2
 			if j < i {
-				np.board[H1] = '.'
-			} else {
-				np.board[A1] = '.'
+				rookFrom = A1
 			}
-			np.board[(i+j)/2] = 'R'
+			np.board.bb[idxR] &^= bit(rookFrom)
+			np.board.bb[idxR] |= bit(rookTo)
+			np.hash ^= zobristPieceSq[idxR][rookFrom] ^ zobristPieceSq[idxR][rookTo]
 		}
 	}
 	if p == 'P' {
-		// Pawn promotion
+		// Pawn promotion; m.promo is 0 for a Move built without one (e.g.
+		// by a caller that doesn't track promotion choice), which defaults
+		// to a queen promotion.
 		if A8 <= j && j <= H8 {
-			np.board[j] = 'Q'
+			promo := m.promo
+			if promo == 0 {
+				promo = 'Q'
+			}
+			promoIdx := pieceIdx(promo)
+			np.board.bb[idxP] &^= bit(j)
+			np.board.bb[promoIdx] |= bit(j)
+			np.hash ^= zobristPieceSq[idxP][j] ^ zobristPieceSq[promoIdx][j]
 		}
 		// First pawn move
 		if j-i == 2*N {
 			np.ep = i + N
 		}
-		// En-passant capture
-		if j == pos.ep {
-			np.board[j+S] = '.'
+		// En-passant capture. pos.ep != 0 keeps a plain a-file promotion
+		// push from aliasing A8, which doubles as ep's "unset" sentinel.
+		if pos.ep != 0 && j == pos.ep {
+			np.board.bb[idxp] &^= bit(j + S)
+			np.hash ^= zobristPieceSq[idxp][j+S]
 		}
 	}
+	np.hash ^= zobristCastleHash(pos.wc, pos.bc) ^ zobristCastleHash(np.wc, np.bc)
+	if pos.ep != 0 {
+		np.hash ^= zobristEp[pos.ep]
+	}
+	if np.ep != 0 {
+		np.hash ^= zobristEp[np.ep]
+	}
 	return np.Flip()
 }
 
-// Value returns the score of the current position if the move is applied.
-func (pos Position) value(m Move) int {
-	pst := map[Piece][120]int{
-		'P': {0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 178, 183, 186, 173, 202, 182, 185, 190, 0, 0, 107, 129, 121, 144, 140, 131, 144, 107, 0, 0, 83, 116, 98, 115, 114, 0, 115, 87, 0, 0, 74, 103, 110, 109, 106, 101, 0, 77, 0, 0, 78, 109, 105, 89, 90, 98, 103, 81, 0, 0, 69, 108, 93, 63, 64, 86, 103, 69, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
-		'N': {0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 214, 227, 205, 205, 270, 225, 222, 210, 0, 0, 277, 274, 380, 244, 284, 342, 276, 266, 0, 0, 290, 347, 281, 354, 353, 307, 342, 278, 0, 0, 304, 304, 325, 317, 313, 321, 305, 297, 0, 0, 279, 285, 311, 301, 302, 315, 282, 0, 0, 0, 262, 290, 293, 302, 298, 295, 291, 266, 0, 0, 257, 265, 282, 0, 282, 0, 257, 260, 0, 0, 206, 257, 254, 256, 261, 245, 258, 211, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
-		'B': {0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 261, 242, 238, 244, 297, 213, 283, 270, 0, 0, 309, 340, 355, 278, 281, 351, 322, 298, 0, 0, 311, 359, 288, 361, 372, 310, 348, 306, 0, 0, 345, 337, 340, 354, 346, 345, 335, 330, 0, 0, 333, 330, 337, 343, 337, 336, 0, 327, 0, 0, 334, 345, 344, 335, 328, 345, 340, 335, 0, 0, 339, 340, 331, 326, 327, 326, 340, 336, 0, 0, 313, 322, 305, 308, 306, 305, 310, 310, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
-		'R': {0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 514, 508, 512, 483, 516, 512, 535, 529, 0, 0, 534, 508, 535, 546, 534, 541, 513, 539, 0, 0, 498, 514, 507, 512, 524, 506, 504, 494, 0, 0, 0, 484, 495, 492, 497, 475, 470, 473, 0, 0, 451, 444, 463, 458, 466, 450, 433, 449, 0, 0, 437, 451, 437, 454, 454, 444, 453, 433, 0, 0, 426, 441, 448, 453, 450, 436, 435, 426, 0, 0, 449, 455, 461, 484, 477, 461, 448, 447, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
-		'Q': {0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 935, 930, 921, 825, 998, 953, 1017, 955, 0, 0, 943, 961, 989, 919, 949, 1005, 986, 953, 0, 0, 927, 972, 961, 989, 1001, 992, 972, 931, 0, 0, 930, 913, 951, 946, 954, 949, 916, 923, 0, 0, 915, 914, 927, 924, 928, 919, 909, 907, 0, 0, 899, 923, 916, 918, 913, 918, 913, 902, 0, 0, 893, 911, 0, 910, 914, 914, 908, 891, 0, 0, 890, 899, 898, 916, 898, 893, 895, 887, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
-		'K': {0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 60004, 60054, 60047, 59901, 59901, 60060, 60083, 59938, 0, 0, 59968, 60010, 60055, 60056, 60056, 60055, 60010, 60003, 0, 0, 59938, 60012, 59943, 60044, 59933, 60028, 60037, 59969, 0, 0, 59945, 60050, 60011, 59996, 59981, 60013, 0, 59951, 0, 0, 59945, 59957, 59948, 59972, 59949, 59953, 59992, 59950, 0, 0, 59953, 59958, 59957, 59921, 59936, 59968, 59971, 59968, 0, 0, 59996, 60003, 59986, 59950, 59943, 59982, 60013, 60004, 0, 0, 60017, 60030, 59997, 59986, 60006, 59999, 60040, 60018, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+// pst holds the piece-square tables used by value and by the UCI layer to
+// score a freshly parsed FEN position, indexed 0..63 (a8..h1).
+var pst = map[Piece][64]int{
+	'P': pst120to64([120]int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 178, 183, 186, 173, 202, 182, 185, 190, 0, 0, 107, 129, 121, 144, 140, 131, 144, 107, 0, 0, 83, 116, 98, 115, 114, 0, 115, 87, 0, 0, 74, 103, 110, 109, 106, 101, 0, 77, 0, 0, 78, 109, 105, 89, 90, 98, 103, 81, 0, 0, 69, 108, 93, 63, 64, 86, 103, 69, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}),
+	'N': pst120to64([120]int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 214, 227, 205, 205, 270, 225, 222, 210, 0, 0, 277, 274, 380, 244, 284, 342, 276, 266, 0, 0, 290, 347, 281, 354, 353, 307, 342, 278, 0, 0, 304, 304, 325, 317, 313, 321, 305, 297, 0, 0, 279, 285, 311, 301, 302, 315, 282, 0, 0, 0, 262, 290, 293, 302, 298, 295, 291, 266, 0, 0, 257, 265, 282, 0, 282, 0, 257, 260, 0, 0, 206, 257, 254, 256, 261, 245, 258, 211, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}),
+	'B': pst120to64([120]int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 261, 242, 238, 244, 297, 213, 283, 270, 0, 0, 309, 340, 355, 278, 281, 351, 322, 298, 0, 0, 311, 359, 288, 361, 372, 310, 348, 306, 0, 0, 345, 337, 340, 354, 346, 345, 335, 330, 0, 0, 333, 330, 337, 343, 337, 336, 0, 327, 0, 0, 334, 345, 344, 335, 328, 345, 340, 335, 0, 0, 339, 340, 331, 326, 327, 326, 340, 336, 0, 0, 313, 322, 305, 308, 306, 305, 310, 310, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}),
+	'R': pst120to64([120]int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 514, 508, 512, 483, 516, 512, 535, 529, 0, 0, 534, 508, 535, 546, 534, 541, 513, 539, 0, 0, 498, 514, 507, 512, 524, 506, 504, 494, 0, 0, 0, 484, 495, 492, 497, 475, 470, 473, 0, 0, 451, 444, 463, 458, 466, 450, 433, 449, 0, 0, 437, 451, 437, 454, 454, 444, 453, 433, 0, 0, 426, 441, 448, 453, 450, 436, 435, 426, 0, 0, 449, 455, 461, 484, 477, 461, 448, 447, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}),
+	'Q': pst120to64([120]int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 935, 930, 921, 825, 998, 953, 1017, 955, 0, 0, 943, 961, 989, 919, 949, 1005, 986, 953, 0, 0, 927, 972, 961, 989, 1001, 992, 972, 931, 0, 0, 930, 913, 951, 946, 954, 949, 916, 923, 0, 0, 915, 914, 927, 924, 928, 919, 909, 907, 0, 0, 899, 923, 916, 918, 913, 918, 913, 902, 0, 0, 893, 911, 0, 910, 914, 914, 908, 891, 0, 0, 890, 899, 898, 916, 898, 893, 895, 887, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}),
+	'K': pst120to64([120]int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 60004, 60054, 60047, 59901, 59901, 60060, 60083, 59938, 0, 0, 59968, 60010, 60055, 60056, 60056, 60055, 60010, 60003, 0, 0, 59938, 60012, 59943, 60044, 59933, 60028, 60037, 59969, 0, 0, 59945, 60050, 60011, 59996, 59981, 60013, 0, 59951, 0, 0, 59945, 59957, 59948, 59972, 59949, 59953, 59992, 59950, 0, 0, 59953, 59958, 59957, 59921, 59936, 59968, 59971, 59968, 0, 0, 59996, 60003, 59986, 59950, 59943, 59982, 60013, 60004, 0, 0, 60017, 60030, 59997, 59986, 60006, 59999, 60040, 60018, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}),
+}
+
+// pst120to64 converts a piece-square table laid out in the old 10x12
+// padded-mailbox scheme (rows 2..9, cols 1..8) into the unpadded 0..63
+// a8..h1 scheme, so the historical table literals above didn't need to be
+// hand-transcribed.
+func pst120to64(old [120]int) (out [64]int) {
+	for row := 2; row < 10; row++ {
+		for col := 1; col < 9; col++ {
+			out[(row-2)*8+(col-1)] = old[row*10+col]
+		}
 	}
+	return out
+}
+
+// boardScore sums piece-square values for every piece on b from the point
+// of view of whichever side is "ours" on that board, i.e. the side to move
+// when b has not been flipped. Used to seed Position.score for a board
+// parsed straight out of FEN, where there is no prior move to derive it
+// from incrementally.
+func boardScore(b Board) int {
+	score := 0
+	for i := 0; i < 64; i++ {
+		p := b.pieceAt(Square(i))
+		if p == '.' {
+			continue
+		}
+		if p.ours() {
+			score += pst[p][i]
+		} else {
+			score -= pst[p.Flip()][Square(i).Flip()]
+		}
+	}
+	return score
+}
 
+// Value returns the score of the current position if the move is applied.
+func (pos Position) value(m Move) int {
 	i, j := m.from, m.to
-	p, q := Piece(pos.board[i]), Piece(pos.board[j])
+	p, q := pos.board.pieceAt(i), pos.board.pieceAt(j)
 	score := pst[p][j] - pst[p][i]
-	if q != '.' && q != ' ' && !q.ours() {
+	if q != '.' {
 		score += pst[q.Flip()][j.Flip()]
 	}
 	// Castling check direction
@@ -303,12 +622,18 @@ func (pos Position) value(m Move) int {
 		}
 	}
 	if p == 'P' {
-		// Pawn promotion to queen
+		// Pawn promotion; defaults to a queen promotion like Position.Move.
 		if A8 <= j && j <= H8 {
-			score += pst['Q'][j] - pst['P'][j]
+			promo := m.promo
+			if promo == 0 {
+				promo = 'Q'
+			}
+			score += pst[promo][j] - pst['P'][j]
 		}
-		// En-passant capture
-		if j == pos.ep {
+		// En-passant capture. The pos.ep != 0 guard keeps a plain a-file
+		// promotion push from aliasing A8, which doubles as ep's "unset"
+		// sentinel (see the phantom-move comment in legal.go).
+		if pos.ep != 0 && j == pos.ep {
 			score += pst['P'][(j + S).Flip()]
 		}
 	}
@@ -320,53 +645,3 @@ var (
 	MaxTableSize  = 10000000
 	EvalRoughness = 13
 )
-
-type entry struct {
-	depth int
-	score int
-	gamma int
-	move  Move
-}
-
-// searcher is an recursive alpha-beta search algorithm
-type Searcher struct {
-	tp    map[Position]entry
-	nodes int
-}
-
-// lowest score in position
-func (s *Searcher) bound(pos Position, gamma, depth int) (int, Move) {
-	s.nodes++
-	if depth <= 0 {
-		return pos.score, Move{}
-	}
-
-	var worstMove Move
-	worstScore := gamma
-
-	for _, m := range pos.Moves() {
-		newPos := pos.Move(m)
-		score, _ := s.bound(newPos, worstScore, depth-1)
-		if score < worstScore { //look for lower scores
-			worstScore = score
-			worstMove = m
-		}
-	}
-	return worstScore, worstMove
-}
-
-// iteratively searches for lowest score
-func (s *Searcher) Search(pos Position, maxNodes int) Move {
-	s.nodes = 0
-	var worstMove Move
-	worstScore := 3 * MateValue
-
-	for depth := 1; depth < 99; depth++ {
-		_, move := s.bound(pos, worstScore, depth)
-		worstMove = move
-		if s.nodes >= maxNodes {
-			break
-		}
-	}
-	return worstMove
-}
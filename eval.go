@@ -0,0 +1,118 @@
+package main
+
+import "math/bits"
+
+// mgPieceValue and egPieceValue are the midgame/endgame material baselines
+// used to taper the piece-square tables below: a piece is worth more or
+// less depending on how much material remains on the board.
+var mgPieceValue = map[Piece]int{'P': 82, 'N': 337, 'B': 365, 'R': 477, 'Q': 1025, 'K': 0}
+var egPieceValue = map[Piece]int{'P': 94, 'N': 281, 'B': 297, 'R': 512, 'Q': 936, 'K': 0}
+
+// phaseWeight is how much each non-pawn, non-king piece contributes to the
+// 24-point game phase: 24 at the start of the game, 0 once only pawns and
+// kings remain.
+var phaseWeight = map[Piece]int{'N': 1, 'B': 1, 'R': 2, 'Q': 4}
+
+const maxPhase = 24
+
+// mgPST and egPST are the midgame/endgame piece-square tables. They reuse
+// the positional shape already tuned into pst (chess.go) and simply rebase
+// it off of pst's own baked-in material (Piece.value, e.g. a flat 100 for
+// a pawn) onto mgPieceValue/egPieceValue above, rather than hand-
+// maintaining a second full set of 64-square tables from scratch.
+var mgPST, egPST map[Piece][64]int
+
+func init() {
+	mgPST = map[Piece][64]int{}
+	egPST = map[Piece][64]int{}
+	for _, p := range []Piece{'P', 'N', 'B', 'R', 'Q', 'K'} {
+		var mg, eg [64]int
+		for sq := 0; sq < 64; sq++ {
+			mg[sq] = pst[p][sq] - p.value() + mgPieceValue[p]
+			eg[sq] = pst[p][sq] - p.value() + egPieceValue[p]
+		}
+		mgPST[p] = mg
+		egPST[p] = eg
+	}
+}
+
+// fileMask[f] has a bit set for every square on file f (0 = a-file).
+var fileMask [8]Bitboard
+
+func init() {
+	for sq := 0; sq < 64; sq++ {
+		fileMask[sq%8] |= bit(Square(sq))
+	}
+}
+
+// doubledPawnPenalty and isolatedPawnPenalty are indexed by the count of
+// doubled (per file) or isolated (total) pawns of one side.
+var doubledPawnPenalty = [9]int{0, -5, -10, -20, -30, -30, -30, -30, -30}
+var isolatedPawnPenalty = [9]int{0, -10, -25, -50, -75, -75, -75, -75, -75}
+
+func clamp8(n int) int {
+	if n > 8 {
+		return 8
+	}
+	return n
+}
+
+// pawnStructurePenalty scores one side's pawns (given as a bitboard of
+// just that side's pawns) via doubled- and isolated-pawn terms.
+func pawnStructurePenalty(pawns Bitboard) int {
+	var perFile [8]int
+	for f := 0; f < 8; f++ {
+		perFile[f] = bits.OnesCount64(uint64(pawns & fileMask[f]))
+	}
+	score := 0
+	isolated := 0
+	for f, count := range perFile {
+		if count == 0 {
+			continue
+		}
+		score += doubledPawnPenalty[clamp8(count)]
+		leftEmpty := f == 0 || perFile[f-1] == 0
+		rightEmpty := f == 7 || perFile[f+1] == 0
+		if leftEmpty && rightEmpty {
+			isolated += count
+		}
+	}
+	score += isolatedPawnPenalty[clamp8(isolated)]
+	return score
+}
+
+// Evaluator computes a tapered midgame/endgame evaluation of a Position,
+// as an alternative to the purely incremental Position.score.
+type Evaluator struct{}
+
+// Evaluate scores pos from the point of view of the side to move, using
+// the same sign convention as Position.score: positive is good for the
+// side to move.
+func (Evaluator) Evaluate(pos Position) int {
+	mg, eg, phase := 0, 0, 0
+	for idx, p := range pieceOrder {
+		bb := pos.board.bb[idx]
+		sign, canonical := 1, p
+		if !p.ours() {
+			sign, canonical = -1, p.Flip()
+		}
+		for bb != 0 {
+			sq := popLSB(&bb)
+			if sign < 0 {
+				sq = sq.Flip()
+			}
+			mg += sign * mgPST[canonical][sq]
+			eg += sign * egPST[canonical][sq]
+			phase += phaseWeight[canonical]
+		}
+	}
+	if phase > maxPhase {
+		phase = maxPhase
+	}
+	score := (mg*phase + eg*(maxPhase-phase)) / maxPhase
+	score += pawnStructurePenalty(pos.board.bb[idxP]) - pawnStructurePenalty(pos.board.bb[idxp])
+	return score
+}
+
+// Evaluate scores pos from the point of view of the side to move.
+func (pos Position) Evaluate() int { return Evaluator{}.Evaluate(pos) }
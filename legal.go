@@ -0,0 +1,169 @@
+package main
+
+import "math/bits"
+
+// enemyAttacks returns every square attacked by the side not to move, given
+// occ to slide through. Callers that want to know where our king may
+// safely step remove the king from occ first, so a king retreating along a
+// checking ray doesn't look like it escaped into a square the slider still
+// covers once the king has actually left.
+func enemyAttacks(pos Position, occ Bitboard) (attacks Bitboard) {
+	b := pos.board
+	knights := b.bb[idxn]
+	for knights != 0 {
+		attacks |= knightAttacks[popLSB(&knights)]
+	}
+	kings := b.bb[idxk]
+	for kings != 0 {
+		attacks |= kingAttacks[popLSB(&kings)]
+	}
+	diag := b.bb[idxb] | b.bb[idxq]
+	for diag != 0 {
+		attacks |= BishopAttacks(popLSB(&diag), occ)
+	}
+	straight := b.bb[idxr] | b.bb[idxq]
+	for straight != 0 {
+		attacks |= RookAttacks(popLSB(&straight), occ)
+	}
+	pawns := b.bb[idxp]
+	for pawns != 0 {
+		i := popLSB(&pawns)
+		for _, d := range [2]Square{S + W, S + E} {
+			if to := i + d; onBoard(to) && fileDist(i, to) == 1 {
+				attacks |= bit(to)
+			}
+		}
+	}
+	return attacks
+}
+
+// checkers returns the squares of every enemy piece currently attacking
+// kingSq, the same pieces inCheck tests for but kept individually so a
+// single check can be resolved by capturing or blocking the right one.
+func checkers(pos Position, kingSq Square, occ Bitboard) (attackers Bitboard) {
+	b := pos.board
+	attackers |= knightAttacks[kingSq] & b.bb[idxn]
+	attackers |= BishopAttacks(kingSq, occ) & (b.bb[idxb] | b.bb[idxq])
+	attackers |= RookAttacks(kingSq, occ) & (b.bb[idxr] | b.bb[idxq])
+	for _, from := range [2]Square{kingSq - S - W, kingSq - S - E} {
+		if onBoard(from) && fileDist(kingSq, from) == 1 && b.bb[idxp]&bit(from) != 0 {
+			attackers |= bit(from)
+		}
+	}
+	return attackers
+}
+
+// pins returns, for every one of our pieces absolutely pinned against
+// king, the ray it may still move along: the pinning slider's square plus
+// every square between it and king. It works by removing each of our
+// pieces lying on a ray from king in turn and checking whether that
+// reveals an enemy slider attacking along the same ray (the classic xray
+// trick), so only a piece that is the sole blocker on its ray is pinned.
+func pins(pos Position, king Square, occ Bitboard) map[Square]Bitboard {
+	result := make(map[Square]Bitboard)
+	b := pos.board
+	rays := [2]struct {
+		attacks func(Square, Bitboard) Bitboard
+		sliders Bitboard
+	}{
+		{BishopAttacks, b.bb[idxb] | b.bb[idxq]},
+		{RookAttacks, b.bb[idxr] | b.bb[idxq]},
+	}
+	for _, ray := range rays {
+		blockers := ray.attacks(king, occ) & b.oursOccupied()
+		for blockers != 0 {
+			sq := popLSB(&blockers)
+			revealed := ray.attacks(king, occ&^bit(sq)) &^ ray.attacks(king, occ) & ray.sliders
+			for revealed != 0 {
+				slider := popLSB(&revealed)
+				result[sq] = rayBetween(king, slider) | bit(slider)
+			}
+		}
+	}
+	return result
+}
+
+// LegalMoves filters Moves, the pseudo-legal generator, down to moves that
+// don't leave pos's own king in check. Double check allows only king moves;
+// single check restricts every other move to capturing the checker or
+// blocking the check ray; a pinned piece may only move along its pin ray.
+// Castling is additionally gated on the king not being in check and every
+// square it crosses not being attacked, since Moves only checks that the
+// path is empty. En-passant captures are verified by replaying the move
+// and testing the result directly, since removing two pawns off the same
+// rank can expose a check that the checkers/pins logic above doesn't
+// model. Moves' phantom diagonal pawn moves onto pos.kp, a search-only
+// hack (see Position.value), are dropped outright since they aren't real
+// chess moves.
+func (pos Position) LegalMoves() []Move {
+	kingBB := pos.board.bb[idxK]
+	if kingBB == 0 {
+		return nil
+	}
+	occ := pos.board.occupied()
+	king := popLSB(&kingBB)
+	danger := enemyAttacks(pos, occ&^bit(king))
+	attackers := checkers(pos, king, occ)
+	numCheckers := bits.OnesCount64(uint64(attackers))
+	blockMask := ^Bitboard(0)
+	if numCheckers == 1 {
+		checker := popLSB(&attackers)
+		blockMask = rayBetween(king, checker) | bit(checker)
+	}
+	pinned := pins(pos, king, occ)
+
+	var legal []Move
+	for _, m := range pos.Moves() {
+		switch p := pos.board.pieceAt(m.from); {
+		case p == 'K' && abs(int(m.to-m.from)) == 2:
+			if numCheckers > 0 || (rayBetween(m.from, m.to)|bit(m.to))&danger != 0 {
+				continue
+			}
+		case p == 'K':
+			if danger&bit(m.to) != 0 {
+				continue
+			}
+		case p == 'P' && pos.ep != 0 && m.to == pos.ep:
+			if inCheck(pos.Move(m).Flip()) {
+				continue
+			}
+		case p == 'P' && fileDist(m.from, m.to) == 1 && pos.board.pieceAt(m.to) == '.':
+			// Moves also generates a diagonal pawn move onto pos.kp (and its
+			// neighbors) or, when neither ep nor kp is actually active, onto
+			// A8 itself, since 0 doubles as both squares' "unset" sentinel;
+			// these are phantom "captures" with nothing to take that exist
+			// only to bias the search away from castling through check (see
+			// the castling-check-direction bonus in Position.value). The
+			// pos.ep != 0 guard above ensures a real en-passant capture onto
+			// A8 can never fall through to here.
+			continue
+		default:
+			if numCheckers >= 2 {
+				continue
+			}
+			if numCheckers == 1 && blockMask&bit(m.to) == 0 {
+				continue
+			}
+			if ray, ok := pinned[m.from]; ok && ray&bit(m.to) == 0 {
+				continue
+			}
+		}
+		legal = append(legal, m)
+	}
+	return legal
+}
+
+// InCheck reports whether pos's side to move has its king attacked.
+func (pos Position) InCheck() bool { return inCheck(pos) }
+
+// IsCheckmate reports whether pos's side to move is in check with no legal
+// reply.
+func (pos Position) IsCheckmate() bool {
+	return pos.InCheck() && len(pos.LegalMoves()) == 0
+}
+
+// IsStalemate reports whether pos's side to move has no legal move but is
+// not in check.
+func (pos Position) IsStalemate() bool {
+	return !pos.InCheck() && len(pos.LegalMoves()) == 0
+}
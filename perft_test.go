@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+// perftCase is one known-good (FEN, depth, node count) triple used to
+// validate Position.Moves; the counts are the standard values quoted across
+// chess engine test suites (e.g. the Chess Programming Wiki's Perft Results
+// page).
+type perftCase struct {
+	name  string
+	fen   string
+	depth int
+	nodes uint64
+}
+
+var perftCases = []perftCase{
+	{"startpos", initialFEN, 5, 4865609},
+	{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq -", 4, 4085603},
+	{"position3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - -", 5, 674624},
+}
+
+func TestPerft(t *testing.T) {
+	for _, c := range perftCases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			pos, _, err := PositionFromFEN(c.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Perft(pos, c.depth); got != c.nodes {
+				t.Errorf("Perft(%q, %d) = %d, want %d", c.fen, c.depth, got, c.nodes)
+			}
+		})
+	}
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Game is a single PGN game: the seven tag roster headers plus the move
+// list in SAN, one entry per half-move (ply).
+type Game struct {
+	Event, Site, Date, Round, White, Black, Result string
+	Moves                                           []string
+}
+
+var pgnTagRe = regexp.MustCompile(`^\[(\w+)\s+"([^"]*)"\]$`)
+var pgnCommentRe = regexp.MustCompile(`\{[^}]*\}`)
+var pgnVariationRe = regexp.MustCompile(`\([^()]*\)`)
+var pgnMoveNumRe = regexp.MustCompile(`^\d+\.+(.*)$`)
+
+// ParsePGN reads zero or more games in PGN text format from r.
+func ParsePGN(r io.Reader) ([]Game, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	var games []Game
+	var cur *Game
+	var movetext strings.Builder
+	inMovetext := false
+
+	finish := func() {
+		if cur == nil {
+			return
+		}
+		parsePGNMovetext(cur, movetext.String())
+		games = append(games, *cur)
+		cur = nil
+		movetext.Reset()
+		inMovetext = false
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if inMovetext {
+				finish()
+			}
+			if cur == nil {
+				cur = &Game{}
+			}
+			if err := parsePGNTag(cur, line); err != nil {
+				return games, err
+			}
+			continue
+		}
+		inMovetext = true
+		movetext.WriteString(line)
+		movetext.WriteByte(' ')
+	}
+	finish()
+	return games, scanner.Err()
+}
+
+func parsePGNTag(g *Game, line string) error {
+	m := pgnTagRe.FindStringSubmatch(line)
+	if m == nil {
+		return fmt.Errorf("pgn: malformed tag line %q", line)
+	}
+	switch m[1] {
+	case "Event":
+		g.Event = m[2]
+	case "Site":
+		g.Site = m[2]
+	case "Date":
+		g.Date = m[2]
+	case "Round":
+		g.Round = m[2]
+	case "White":
+		g.White = m[2]
+	case "Black":
+		g.Black = m[2]
+	case "Result":
+		g.Result = m[2]
+	}
+	return nil
+}
+
+// parsePGNMovetext strips comments, variations and move numbers from text
+// and appends whatever SAN tokens remain to g.Moves, recording the game
+// result token (if any) into g.Result.
+func parsePGNMovetext(g *Game, text string) {
+	text = pgnCommentRe.ReplaceAllString(text, " ")
+	for strings.ContainsAny(text, "()") {
+		next := pgnVariationRe.ReplaceAllString(text, " ")
+		if next == text {
+			break
+		}
+		text = next
+	}
+	for _, tok := range strings.Fields(text) {
+		if m := pgnMoveNumRe.FindStringSubmatch(tok); m != nil {
+			tok = m[1]
+			if tok == "" {
+				continue
+			}
+		}
+		if strings.HasPrefix(tok, "$") {
+			continue
+		}
+		switch tok {
+		case "1-0", "0-1", "1/2-1/2", "*":
+			if g.Result == "" {
+				g.Result = tok
+			}
+			continue
+		}
+		g.Moves = append(g.Moves, tok)
+	}
+}
+
+// String renders g back to PGN text: the seven tag roster followed by
+// numbered movetext.
+func (g Game) String() string {
+	var b strings.Builder
+	tag := func(name, value string) { fmt.Fprintf(&b, "[%s %q]\n", name, value) }
+	tag("Event", g.Event)
+	tag("Site", g.Site)
+	tag("Date", g.Date)
+	tag("Round", g.Round)
+	tag("White", g.White)
+	tag("Black", g.Black)
+	result := g.Result
+	if result == "" {
+		result = "*"
+	}
+	tag("Result", result)
+	b.WriteByte('\n')
+
+	col := 0
+	for i, mv := range g.Moves {
+		if i%2 == 0 {
+			fmt.Fprintf(&b, "%d. ", i/2+1)
+		}
+		b.WriteString(mv)
+		b.WriteByte(' ')
+		if col++; col == 8 {
+			b.WriteByte('\n')
+			col = 0
+		}
+	}
+	b.WriteString(result)
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// GameState replays a game's moves against Position, tracking the absolute
+// color, ply count, and halfmove clock that Position itself doesn't carry
+// (it is always rendered relative to the side to move, see uci.go), so PGN
+// and FEN's absolute squares and move counters can be produced and
+// consumed.
+type GameState struct {
+	pos      Position
+	color    Color
+	ply      int
+	halfmove int      // half-moves since the last pawn move or capture
+	history  []uint64 // HashKey(pos, color) after every ply, plus the starting position, for threefold-repetition detection
+}
+
+// NewGameState returns a GameState at the standard starting position.
+func NewGameState() *GameState {
+	pos, color, _ := PositionFromFEN(initialFEN)
+	return &GameState{pos: pos, color: color, history: []uint64{HashKey(pos, int(color))}}
+}
+
+// NewGameStateFromFEN returns a GameState starting from fen, for replaying
+// or emitting a game that didn't start from the standard position.
+func NewGameStateFromFEN(fen string) (*GameState, error) {
+	pos, color, err := PositionFromFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	return &GameState{pos: pos, color: color, history: []uint64{HashKey(pos, int(color))}}, nil
+}
+
+func (gs *GameState) Position() Position { return gs.pos }
+func (gs *GameState) Color() Color       { return gs.color }
+func (gs *GameState) Ply() int           { return gs.ply }
+
+// FEN renders the current position as a full FEN record.
+func (gs *GameState) FEN() string {
+	return gs.pos.FEN(gs.color, gs.halfmove, gs.ply/2+1)
+}
+
+// Push parses san against the current position, applies it, and advances
+// the game state.
+func (gs *GameState) Push(san string) error {
+	m, err := gs.parseSAN(san)
+	if err != nil {
+		return err
+	}
+	gs.applyMove(m)
+	return nil
+}
+
+// PushMove applies m directly, returning the SAN text for it.
+func (gs *GameState) PushMove(m Move) string {
+	san := gs.SAN(m)
+	gs.applyMove(m)
+	return san
+}
+
+func (gs *GameState) applyMove(m Move) {
+	if gs.pos.board.pieceAt(m.from) == 'P' || gs.pos.board.pieceAt(m.to) != '.' {
+		gs.halfmove = 0
+	} else {
+		gs.halfmove++
+	}
+	gs.pos = gs.pos.Move(m)
+	gs.color = 1 - gs.color
+	gs.ply++
+	gs.history = append(gs.history, HashKey(gs.pos, int(gs.color)))
+}
+
+// IsThreefoldRepetition reports whether the current position has occurred
+// at least three times in gs's history (including the current position),
+// identified by Position's Zobrist hash keyed by absolute color: plain
+// pos.hash can't tell a position apart from its exact color-mirror
+// reflection (see zobristSide), which would otherwise inflate the count.
+func (gs *GameState) IsThreefoldRepetition() bool {
+	current := HashKey(gs.pos, int(gs.color))
+	count := 0
+	for _, h := range gs.history {
+		if h == current {
+			count++
+		}
+	}
+	return count >= 3
+}
+
+// IsFiftyMoveRule reports whether 50 full moves (100 half-moves) have
+// passed since the last pawn move or capture.
+func (gs *GameState) IsFiftyMoveRule() bool { return gs.halfmove >= 100 }
+
+// IsDraw reports whether the game is drawn: by the threefold-repetition or
+// fifty-move rules, or because the side to move has no legal move and is
+// not in check (stalemate).
+func (gs *GameState) IsDraw() bool {
+	return gs.IsThreefoldRepetition() || gs.IsFiftyMoveRule() || gs.pos.IsStalemate()
+}
+
+var sanRe = regexp.MustCompile(`^([KQRBN])?([a-h])?([1-8])?(x)?([a-h][1-8])(=[QRBN])?[+#]?$`)
+
+// parseSAN resolves a SAN token against gs's current position, returning
+// the matching legal move. A missing promotion suffix (e.g. plain "e8",
+// which isn't strictly legal SAN but shows up in casual game text)
+// defaults to queen, matching Position.Move's own default.
+func (gs *GameState) parseSAN(san string) (Move, error) {
+	san = strings.TrimRight(san, "+#!?")
+	switch san {
+	case "O-O", "0-0":
+		return gs.castlingMove(false)
+	case "O-O-O", "0-0-0":
+		return gs.castlingMove(true)
+	}
+
+	m := sanRe.FindStringSubmatch(san)
+	if m == nil {
+		return Move{}, fmt.Errorf("pgn: unrecognized move %q", san)
+	}
+	pieceLetter, disambFile, disambRank, dest, promoSuffix := m[1], m[2], m[3], m[5], m[6]
+	piece := Piece('P')
+	if pieceLetter != "" {
+		piece = Piece(pieceLetter[0])
+	}
+	promo := Piece('Q')
+	if promoSuffix != "" {
+		promo = Piece(promoSuffix[1])
+	}
+	absTo, ok := parseSquare(dest)
+	if !ok {
+		return Move{}, fmt.Errorf("pgn: bad destination square in %q", san)
+	}
+	to := flipIf(absTo, gs.color)
+
+	var candidates []Move
+	for _, mv := range gs.pos.LegalMoves() {
+		if mv.to != to || gs.pos.board.pieceAt(mv.from) != piece {
+			continue
+		}
+		if piece == 'P' && A8 <= mv.to && mv.to <= H8 && mv.promo != promo {
+			continue
+		}
+		from := flipIf(mv.from, gs.color).String()
+		if disambFile != "" && from[0:1] != disambFile {
+			continue
+		}
+		if disambRank != "" && from[1:2] != disambRank {
+			continue
+		}
+		candidates = append(candidates, mv)
+	}
+	switch len(candidates) {
+	case 0:
+		return Move{}, fmt.Errorf("pgn: no legal move matches %q", san)
+	case 1:
+		return candidates[0], nil
+	default:
+		return Move{}, fmt.Errorf("pgn: ambiguous move %q", san)
+	}
+}
+
+// castlingMove finds the king move among the current pseudo-legal moves
+// that castles queenside or kingside.
+func (gs *GameState) castlingMove(queenside bool) (Move, error) {
+	for _, mv := range gs.pos.Moves() {
+		if gs.pos.board.pieceAt(mv.from) != 'K' {
+			continue
+		}
+		switch d := int(mv.to - mv.from); {
+		case queenside && d == 2*W:
+			return mv, nil
+		case !queenside && d == 2*E:
+			return mv, nil
+		}
+	}
+	side := "kingside"
+	if queenside {
+		side = "queenside"
+	}
+	return Move{}, fmt.Errorf("pgn: no %s castling move available", side)
+}
+
+// SAN returns the SAN text for the pseudo-legal move m from gs's current
+// position, without applying it.
+func (gs *GameState) SAN(m Move) string {
+	p := gs.pos.board.pieceAt(m.from)
+	if p == 'K' && abs(int(m.to-m.from)) == 2 {
+		if m.to > m.from {
+			return gs.suffixed(m, "O-O")
+		}
+		return gs.suffixed(m, "O-O-O")
+	}
+
+	capture := gs.pos.board.pieceAt(m.to) != '.' || (p == 'P' && gs.pos.ep != 0 && m.to == gs.pos.ep)
+	dest := flipIf(m.to, gs.color).String()
+
+	var b strings.Builder
+	if p == 'P' {
+		if capture {
+			b.WriteString(flipIf(m.from, gs.color).String()[0:1])
+			b.WriteByte('x')
+		}
+		b.WriteString(dest)
+		if A8 <= m.to && m.to <= H8 {
+			promo := m.promo
+			if promo == 0 {
+				promo = 'Q'
+			}
+			b.WriteByte('=')
+			b.WriteByte(byte(promo))
+		}
+	} else {
+		b.WriteByte(byte(p))
+		if needFile, needRank := gs.disambiguate(p, m); needFile || needRank {
+			from := flipIf(m.from, gs.color).String()
+			if needFile {
+				b.WriteString(from[0:1])
+			}
+			if needRank {
+				b.WriteString(from[1:2])
+			}
+		}
+		if capture {
+			b.WriteByte('x')
+		}
+		b.WriteString(dest)
+	}
+	return gs.suffixed(m, b.String())
+}
+
+// disambiguate reports whether m's SAN needs its origin file and/or rank
+// spelled out because another piece of the same type can also legally
+// reach m.to: no disambiguation is needed unless such a candidate exists,
+// file alone suffices unless another candidate shares it, rank alone
+// suffices unless another candidate shares that, and both are needed if
+// neither alone is unique.
+func (gs *GameState) disambiguate(p Piece, m Move) (needFile, needRank bool) {
+	hasOther, sameFile, sameRank := false, false, false
+	for _, other := range gs.pos.LegalMoves() {
+		if other.to != m.to || other.from == m.from || gs.pos.board.pieceAt(other.from) != p {
+			continue
+		}
+		hasOther = true
+		if other.from%8 == m.from%8 {
+			sameFile = true
+		}
+		if other.from/8 == m.from/8 {
+			sameRank = true
+		}
+	}
+	switch {
+	case !hasOther:
+		return false, false
+	case !sameFile:
+		return true, false
+	case !sameRank:
+		return false, true
+	default:
+		return true, true
+	}
+}
+
+// suffixed appends PGN's "+"/"#" check/mate suffix to san, computed by
+// applying m to a scratch copy of the position.
+func (gs *GameState) suffixed(m Move, san string) string {
+	np := gs.pos.Move(m)
+	if !np.InCheck() {
+		return san
+	}
+	if np.IsCheckmate() {
+		return san + "#"
+	}
+	return san + "+"
+}
+
+// NewGame builds a Game from a sequence of moves played from the standard
+// starting position, generating SAN text for each one.
+func NewGame(event, site, date, round, white, black string, moves []Move) Game {
+	gs := NewGameState()
+	g := Game{Event: event, Site: site, Date: date, Round: round, White: white, Black: black}
+	for _, m := range moves {
+		g.Moves = append(g.Moves, gs.PushMove(m))
+	}
+	return g
+}
@@ -0,0 +1,229 @@
+package main
+
+import "sort"
+
+// Transposition table entry flags: whether score is the exact value, or
+// only a lower/upper bound because a cutoff pruned the rest of the search.
+const (
+	flagExact = iota
+	flagLower
+	flagUpper
+)
+
+type entry struct {
+	depth int
+	score int
+	flag  int
+	move  Move
+}
+
+// Searcher is a negamax alpha-beta search with transposition-table probing,
+// MVV-LVA-ordered moves, and a capture-only quiescence search at the
+// horizon. The zero value is ready to use.
+type Searcher struct {
+	tp       map[uint64]entry
+	nodes    int
+	maxNodes int
+}
+
+// orderMoves sorts moves in place so the transposition-table move (if any)
+// is searched first, then captures in roughly MVV-LVA order (using
+// Position.value as the capture-value proxy), which cuts the alpha-beta
+// search far more often than searching in generation order.
+func orderMoves(pos Position, moves []Move, ttMove Move) {
+	sort.SliceStable(moves, func(i, j int) bool {
+		if moves[i] == ttMove {
+			return moves[j] != ttMove
+		}
+		if moves[j] == ttMove {
+			return false
+		}
+		return pos.value(moves[i]) > pos.value(moves[j])
+	})
+}
+
+// isNoisy reports whether m is a capture, en-passant capture, or promotion,
+// the only moves quiescence search considers beyond the stand-pat score.
+func isNoisy(pos Position, m Move) bool {
+	if pos.board.theirsOccupied()&bit(m.to) != 0 {
+		return true
+	}
+	if m.to == pos.ep && pos.board.pieceAt(m.from) == 'P' {
+		return true
+	}
+	return A8 <= m.to && m.to <= H8 && pos.board.pieceAt(m.from) == 'P'
+}
+
+// quiescence extends the search along capture/promotion lines only, with a
+// stand-pat cutoff based on Evaluate, to avoid the horizon effect where the
+// static eval is taken mid-exchange.
+func (s *Searcher) quiescence(pos Position, alpha, beta int) int {
+	s.nodes++
+	standPat := pos.Evaluate()
+	if standPat >= beta {
+		return beta
+	}
+	if standPat > alpha {
+		alpha = standPat
+	}
+	moves := pos.Moves()
+	orderMoves(pos, moves, Move{})
+	for _, m := range moves {
+		if !isNoisy(pos, m) {
+			continue
+		}
+		score := -s.quiescence(pos.Move(m), -beta, -alpha)
+		if score >= beta {
+			return beta
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+	return alpha
+}
+
+// bound is a negamax alpha-beta search returning the score of pos from the
+// side-to-move's point of view. depth <= 0 drops into quiescence. ply is
+// the distance from the search root, used to key the transposition table
+// so that pos and its exact color-mirror reflection (which hash
+// identically, see zobristSide) never collide: mirrored positions always
+// fall on opposite sides of the root, so ply parity alone tells them
+// apart.
+func (s *Searcher) bound(pos Position, alpha, beta, depth, ply int) int {
+	if s.tp == nil {
+		s.tp = make(map[uint64]entry)
+	}
+	s.nodes++
+	if s.maxNodes > 0 && s.nodes >= s.maxNodes {
+		return pos.score
+	}
+
+	key := HashKey(pos, ply)
+	alphaOrig := alpha
+	var ttMove Move
+	if e, ok := s.tp[key]; ok {
+		ttMove = e.move
+		if e.depth >= depth {
+			switch e.flag {
+			case flagExact:
+				return e.score
+			case flagLower:
+				if e.score > alpha {
+					alpha = e.score
+				}
+			case flagUpper:
+				if e.score < beta {
+					beta = e.score
+				}
+			}
+			if alpha >= beta {
+				return e.score
+			}
+		}
+	}
+
+	if depth <= 0 {
+		return s.quiescence(pos, alpha, beta)
+	}
+
+	moves := pos.Moves()
+	if len(moves) == 0 {
+		return pos.score
+	}
+	orderMoves(pos, moves, ttMove)
+
+	best := -3 * MateValue
+	bestMove := moves[0]
+	for _, m := range moves {
+		score := -s.bound(pos.Move(m), -beta, -alpha, depth-1, ply+1)
+		if score > best {
+			best = score
+			bestMove = m
+		}
+		if best > alpha {
+			alpha = best
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+
+	flag := flagExact
+	if best <= alphaOrig {
+		flag = flagUpper
+	} else if best >= beta {
+		flag = flagLower
+	}
+	s.tp[key] = entry{depth: depth, score: best, flag: flag, move: bestMove}
+	return best
+}
+
+// PV reconstructs the principal variation for pos out of the transposition
+// table filled by the most recent Search/searchDepth call, following each
+// ply's stored best move until the table has no entry for it or maxLen
+// moves have been collected.
+func (s *Searcher) PV(pos Position, maxLen int) []Move {
+	var pv []Move
+	for ply := 0; ply < maxLen; ply++ {
+		e, ok := s.tp[HashKey(pos, ply)]
+		if !ok || e.move == (Move{}) {
+			break
+		}
+		pv = append(pv, e.move)
+		pos = pos.Move(e.move)
+	}
+	return pv
+}
+
+// Search runs iterative deepening with aspiration windows around the
+// previous iteration's score (widening by EvalRoughness on fail-high or
+// fail-low), stopping once maxNodes is reached.
+func (s *Searcher) Search(pos Position, maxNodes int) Move {
+	s.nodes = 0
+	s.maxNodes = maxNodes
+	s.tp = make(map[uint64]entry)
+
+	var best Move
+	score := 0
+	for depth := 1; depth < 99; depth++ {
+		score, best = s.searchDepth(pos, depth, score)
+		if s.nodes >= maxNodes {
+			break
+		}
+	}
+	return best
+}
+
+// searchDepth runs one iterative-deepening step at depth, widening the
+// aspiration window around lastScore until the result falls strictly
+// inside it (or the node budget runs out), and returns the score and best
+// move recorded in the transposition table for pos.
+func (s *Searcher) searchDepth(pos Position, depth, lastScore int) (int, Move) {
+	alpha, beta := lastScore-EvalRoughness, lastScore+EvalRoughness
+	var score int
+	for {
+		score = s.bound(pos, alpha, beta, depth, 0)
+		if score <= alpha {
+			alpha -= EvalRoughness * 2
+		} else if score >= beta {
+			beta += EvalRoughness * 2
+		} else {
+			break
+		}
+		if alpha < -3*MateValue {
+			alpha = -3 * MateValue
+		}
+		if beta > 3*MateValue {
+			beta = 3 * MateValue
+		}
+		if s.maxNodes > 0 && s.nodes >= s.maxNodes {
+			break
+		}
+	}
+	move := Move{}
+	if e, ok := s.tp[HashKey(pos, 0)]; ok {
+		move = e.move
+	}
+	return score, move
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Color identifies which side is actually to move in the real game. Position
+// itself has no notion of color: it is always rendered from the point of
+// view of the side to move, flipping after every ply.
+type Color int
+
+const (
+	White Color = iota
+	Black
+)
+
+// initialFEN is the standard starting position.
+const initialFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -"
+
+// goParams holds the parsed options of a UCI "go" command.
+type goParams struct {
+	wtime, btime, winc, binc int
+	movetime                 int
+	depth                    int
+	nodes                    int
+}
+
+// UCIEngine drives the Universal Chess Interface protocol on top of
+// Position/Searcher. It tracks which color is actually to move so absolute
+// algebraic moves (as sent by a GUI) can be translated to and from the
+// side-to-move-relative squares that Position uses internally.
+type UCIEngine struct {
+	in  *bufio.Scanner
+	out io.Writer
+
+	pos   Position
+	color Color
+
+	stopping atomic.Bool
+	done     chan struct{}
+}
+
+// NewUCIEngine builds a UCI engine reading commands from r and writing
+// responses to w.
+func NewUCIEngine(r io.Reader, w io.Writer) *UCIEngine {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	return &UCIEngine{in: scanner, out: w}
+}
+
+// Run reads commands until "quit" or EOF.
+func (u *UCIEngine) Run() {
+	u.setPosition(initialFEN, nil)
+	for u.in.Scan() {
+		line := strings.TrimSpace(u.in.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "uci":
+			fmt.Fprintln(u.out, "id name dumb-chess")
+			fmt.Fprintln(u.out, "id author dronavallipranav")
+			fmt.Fprintln(u.out, "uciok")
+		case "isready":
+			fmt.Fprintln(u.out, "readyok")
+		case "ucinewgame":
+			u.setPosition(initialFEN, nil)
+		case "position":
+			u.handlePosition(fields[1:])
+		case "go":
+			u.handleGo(fields[1:])
+		case "stop":
+			u.stopping.Store(true)
+			u.waitSearch()
+		case "quit":
+			u.waitSearch()
+			return
+		}
+	}
+	u.waitSearch()
+}
+
+// waitSearch blocks until any in-flight "go" search has reported its
+// bestmove, so the engine never exits mid-search.
+func (u *UCIEngine) waitSearch() {
+	if u.done != nil {
+		<-u.done
+		u.done = nil
+	}
+}
+
+// setPosition resets the engine to fen (board+side only, the rest of the FEN
+// record is not needed here) and replays moves, each in long algebraic
+// notation such as "e2e4" or "e7e8q".
+func (u *UCIEngine) setPosition(fen string, moves []string) {
+	pos, color, err := PositionFromFEN(fen)
+	if err != nil {
+		fmt.Fprintln(u.out, "info string invalid fen:", err)
+		return
+	}
+	u.pos = pos
+	u.color = color
+	for _, mv := range moves {
+		m, ok := u.parseMove(mv)
+		if !ok {
+			fmt.Fprintln(u.out, "info string illegal move in position command:", mv)
+			return
+		}
+		u.pos = u.pos.Move(m)
+		u.color = 1 - u.color
+	}
+}
+
+func (u *UCIEngine) handlePosition(fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+	var fen string
+	rest := fields
+	switch fields[0] {
+	case "startpos":
+		fen = initialFEN
+		rest = fields[1:]
+	case "fen":
+		rest = fields[1:]
+		i := 0
+		for i < len(rest) && rest[i] != "moves" {
+			i++
+		}
+		fen = strings.Join(rest[:i], " ")
+		rest = rest[i:]
+	default:
+		return
+	}
+	var moves []string
+	if len(rest) > 0 && rest[0] == "moves" {
+		moves = rest[1:]
+	}
+	u.setPosition(fen, moves)
+}
+
+// parseSquare converts an absolute algebraic square such as "e2" into the
+// Square indexing used by Board, always from White's point of view.
+func parseSquare(s string) (Square, bool) {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return 0, false
+	}
+	col := int(s[0] - 'a')
+	row := 8 - int(s[1]-'0')
+	return Square(row*8 + col), true
+}
+
+// parseMove converts a long algebraic move (e.g. "e2e4", "e7e8q") sent by
+// the GUI into a Move in the engine's current side-to-move-relative square
+// numbering. The promotion suffix, if any, names the piece regardless of
+// color (it's always "ours" doing the promoting), so it needs no flipping.
+func (u *UCIEngine) parseMove(s string) (Move, bool) {
+	if len(s) < 4 {
+		return Move{}, false
+	}
+	from, ok := parseSquare(s[0:2])
+	if !ok {
+		return Move{}, false
+	}
+	to, ok := parseSquare(s[2:4])
+	if !ok {
+		return Move{}, false
+	}
+	if u.color == Black {
+		from, to = from.Flip(), to.Flip()
+	}
+	m := Move{from: from, to: to}
+	if len(s) >= 5 {
+		m.promo = Piece(s[4] + 'A' - 'a')
+	}
+	return m, true
+}
+
+// renderMove converts an internal, side-to-move-relative Move back into
+// absolute long algebraic notation for the given color to move.
+func renderMove(m Move, color Color) string {
+	from, to := m.from, m.to
+	if color == Black {
+		from, to = from.Flip(), to.Flip()
+	}
+	s := from.String() + to.String()
+	if m.promo != 0 {
+		s += string([]byte{byte(m.promo) + 'a' - 'A'})
+	}
+	return s
+}
+
+// renderPV renders a principal variation (as returned by Searcher.PV) into
+// UCI's space-separated absolute long algebraic notation, alternating
+// color starting from the side to move at the root.
+func renderPV(moves []Move, color Color) string {
+	parts := make([]string, len(moves))
+	for i, m := range moves {
+		parts[i] = renderMove(m, color)
+		color = 1 - color
+	}
+	return strings.Join(parts, " ")
+}
+
+func (u *UCIEngine) handleGo(fields []string) {
+	var p goParams
+	for i := 0; i < len(fields); i++ {
+		var target *int
+		switch fields[i] {
+		case "wtime":
+			target = &p.wtime
+		case "btime":
+			target = &p.btime
+		case "winc":
+			target = &p.winc
+		case "binc":
+			target = &p.binc
+		case "movetime":
+			target = &p.movetime
+		case "depth":
+			target = &p.depth
+		case "nodes":
+			target = &p.nodes
+		default:
+			continue
+		}
+		if i+1 < len(fields) {
+			if v, err := strconv.Atoi(fields[i+1]); err == nil {
+				*target = v
+				i++
+			}
+		}
+	}
+
+	u.waitSearch()
+	u.stopping.Store(false)
+	u.done = make(chan struct{})
+	pos, color := u.pos, u.color
+	go func() {
+		defer close(u.done)
+		best := u.think(pos, color, p)
+		fmt.Fprintln(u.out, "bestmove", renderMove(best, color))
+	}()
+}
+
+// think runs iterative deepening, printing an "info" line after each
+// completed depth, and stops when it runs out of time, nodes, requested
+// depth, or is asked to via "stop".
+func (u *UCIEngine) think(pos Position, color Color, p goParams) Move {
+	s := &Searcher{}
+
+	maxNodes := p.nodes
+	if maxNodes <= 0 {
+		maxNodes = 1 << 20
+	}
+	maxDepth := p.depth
+	if maxDepth <= 0 {
+		maxDepth = 60
+	}
+
+	var deadline time.Time
+	if p.movetime > 0 {
+		deadline = time.Now().Add(time.Duration(p.movetime) * time.Millisecond)
+	} else if p.wtime > 0 || p.btime > 0 {
+		myTime, myInc := p.wtime, p.winc
+		if color == Black {
+			myTime, myInc = p.btime, p.binc
+		}
+		budget := myTime/20 + myInc/2
+		if budget <= 0 {
+			budget = 100
+		}
+		deadline = time.Now().Add(time.Duration(budget) * time.Millisecond)
+	}
+
+	s.maxNodes = maxNodes
+
+	var best Move
+	score := 0
+	for depth := 1; depth <= maxDepth; depth++ {
+		var move Move
+		score, move = s.searchDepth(pos, depth, score)
+		if move != (Move{}) {
+			best = move
+		}
+		pv := renderPV(s.PV(pos, depth), color)
+		if pv == "" {
+			pv = renderMove(best, color)
+		}
+		fmt.Fprintf(u.out, "info depth %d score cp %d nodes %d pv %s\n", depth, score, s.nodes, pv)
+		if s.nodes >= maxNodes {
+			break
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			break
+		}
+		if u.stopping.Load() {
+			break
+		}
+	}
+	return best
+}
+
+func main() {
+	NewUCIEngine(os.Stdin, os.Stdout).Run()
+}
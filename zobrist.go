@@ -0,0 +1,91 @@
+package main
+
+import "math/rand"
+
+// zobristPieceSq holds one random 64-bit value per (piece, square). It is
+// built mirror-symmetric across the ours/theirs split (slot i in 0..5
+// shares its value with slot i+6 at the mirrored square) so that a
+// Position's hash is unaffected by Board.Flip: the same physical
+// arrangement hashes the same whether it is white's or black's "ours".
+var zobristPieceSq [12][64]uint64
+
+// zobristCastle holds one value per castling right (index 0 = a-side,
+// 1 = h-side), shared between wc and bc for the same reason: Flip swaps
+// wc and bc, so the hash must not depend on which side is which.
+var zobristCastle [2]uint64
+
+// zobristEp holds one value per en-passant square, mirrored the same way
+// (zobristEp[s] == zobristEp[s.Flip()]) since Flip also flips pos.ep.
+var zobristEp [64]uint64
+
+// zobristSide holds two values used to tell a position apart from its
+// exact 180-degree/color-mirror reflection, which zobristPieceSq/zobristEp
+// otherwise hash identically (that's the point of their symmetry: it's
+// what lets Position.Flip carry pos.hash through unchanged). Flip itself
+// must never touch pos.hash, so callers that need a collision-safe key —
+// the search transposition table, keyed by ply parity, and GameState's
+// repetition history, keyed by absolute color — XOR in zobristSide
+// themselves; see HashKey.
+var zobristSide [2]uint64
+
+func init() {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 6; i++ {
+		for sq := 0; sq < 64; sq++ {
+			v := rnd.Uint64()
+			zobristPieceSq[i][sq] = v
+			zobristPieceSq[i+6][63-sq] = v
+		}
+	}
+	for i := range zobristCastle {
+		zobristCastle[i] = rnd.Uint64()
+	}
+	for sq := 0; sq < 32; sq++ {
+		v := rnd.Uint64()
+		zobristEp[sq] = v
+		zobristEp[63-sq] = v
+	}
+	for i := range zobristSide {
+		zobristSide[i] = rnd.Uint64()
+	}
+}
+
+// HashKey returns pos.hash disambiguated against its own color-mirror
+// reflection by orientation, a 0/1 value the caller picks consistently
+// (ply parity during one search, absolute color across a game) so that
+// orientation 0 always means the same thing at every call site sharing a
+// table.
+func HashKey(pos Position, orientation int) uint64 {
+	return pos.hash ^ zobristSide[orientation&1]
+}
+
+// zobristCastleHash returns the combined castling-rights contribution to
+// the Zobrist hash.
+func zobristCastleHash(wc, bc [2]bool) (h uint64) {
+	for i := 0; i < 2; i++ {
+		if wc[i] {
+			h ^= zobristCastle[i]
+		}
+		if bc[i] {
+			h ^= zobristCastle[i]
+		}
+	}
+	return h
+}
+
+// computeHash computes a Position's Zobrist hash from scratch. Only
+// needed to seed a position built directly from FEN; Position.Move keeps
+// pos.hash up to date incrementally from there.
+func computeHash(pos Position) (h uint64) {
+	for idx := 0; idx < 12; idx++ {
+		bb := pos.board.bb[idx]
+		for bb != 0 {
+			h ^= zobristPieceSq[idx][popLSB(&bb)]
+		}
+	}
+	h ^= zobristCastleHash(pos.wc, pos.bc)
+	if pos.ep != 0 {
+		h ^= zobristEp[pos.ep]
+	}
+	return h
+}